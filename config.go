@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// repoConfigFile is the name of the per-repo config file, expected at the
+// root of the working tree.
+const repoConfigFile = ".git-clean.yaml"
+
+// cleanConfig holds the settings read from the repo and global config files.
+// Protected and DefaultBranch are merged: Protected from both files is
+// combined, and DefaultBranch from the repo config wins over the global one.
+type cleanConfig struct {
+	Protected     []string `yaml:"protected"`
+	DefaultBranch string   `yaml:"default_branch"`
+}
+
+// loadConfig reads the global config from $XDG_CONFIG_HOME/git-clean/config.yaml
+// and the repo config from <repoRoot>/.git-clean.yaml, merging the two.
+// Either file may be absent, in which case it contributes nothing.
+func loadConfig(repoRoot string) (cleanConfig, error) {
+	var merged cleanConfig
+
+	global, err := readConfigFile(globalConfigPath())
+	if err != nil {
+		return cleanConfig{}, err
+	}
+	merged.Protected = append(merged.Protected, global.Protected...)
+	merged.DefaultBranch = global.DefaultBranch
+
+	repoCfg, err := readConfigFile(filepath.Join(repoRoot, repoConfigFile))
+	if err != nil {
+		return cleanConfig{}, err
+	}
+	merged.Protected = append(merged.Protected, repoCfg.Protected...)
+	if repoCfg.DefaultBranch != "" {
+		merged.DefaultBranch = repoCfg.DefaultBranch
+	}
+
+	return merged, nil
+}
+
+func globalConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "git-clean", "config.yaml")
+}
+
+func readConfigFile(path string) (cleanConfig, error) {
+	if path == "" {
+		return cleanConfig{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cleanConfig{}, nil
+	}
+	if err != nil {
+		return cleanConfig{}, fmt.Errorf("reading config %s: %w", path, err)
+	}
+
+	var c cleanConfig
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return cleanConfig{}, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// protectedMatcher answers whether a branch name is protected, matching
+// either an exact name or one of the compiled regex patterns.
+type protectedMatcher struct {
+	exact    map[string]struct{}
+	patterns []*regexp.Regexp
+}
+
+// regexMetachars are the characters that make an entry look like a regex
+// pattern rather than a plain branch name, e.g. "release/.*" or "hotfix/.*".
+const regexMetachars = `\^$.|?*+()[]{}`
+
+// compileProtected compiles the protected entries from a config. An entry
+// that contains no regex metacharacters (e.g. "develop") is matched as an
+// exact branch name. An entry that does (e.g. "release/.*") is compiled as
+// an anchored regex; if it isn't valid regex syntax, it falls back to an
+// exact match instead of failing the whole config load, so an entry like
+// "feature[1" (a perfectly valid branch name) still protects that branch.
+func compileProtected(entries []string) protectedMatcher {
+	m := protectedMatcher{exact: map[string]struct{}{}}
+	for _, e := range entries {
+		if !strings.ContainsAny(e, regexMetachars) {
+			m.exact[e] = struct{}{}
+			continue
+		}
+		re, err := regexp.Compile("^" + e + "$")
+		if err != nil {
+			m.exact[e] = struct{}{}
+			continue
+		}
+		m.patterns = append(m.patterns, re)
+	}
+	return m
+}
+
+func (m protectedMatcher) match(branch string) bool {
+	if _, ok := m.exact[branch]; ok {
+		return true
+	}
+	for _, re := range m.patterns {
+		if re.MatchString(branch) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestCompileProtectedMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		branch  string
+		protect bool
+	}{
+		{"exact match", []string{"develop"}, "develop", true},
+		{"exact mismatch", []string{"develop"}, "feature", false},
+		{"pattern match", []string{"release/.*"}, "release/1.0", true},
+		{"pattern mismatch", []string{"release/.*"}, "feature/1.0", false},
+		{"pattern anchored, no partial match", []string{"release/.*"}, "old-release/1.0", false},
+		{"invalid regex falls back to literal", []string{"feature[1"}, "feature[1", true},
+		{"invalid regex literal mismatch", []string{"feature[1"}, "feature[2", false},
+		{"no entries", nil, "develop", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := compileProtected(tt.entries)
+			if got := m.match(tt.branch); got != tt.protect {
+				t.Errorf("match(%q) with entries %v = %v, want %v", tt.branch, tt.entries, got, tt.protect)
+			}
+		})
+	}
+}
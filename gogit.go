@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// goGitRepo implements repo using go-git instead of shelling out to git, so
+// git-clean can run on hosts without a git binary installed.
+type goGitRepo struct {
+	repo *git.Repository
+}
+
+func newGoGitRepo(path string) *goGitRepo {
+	r, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[error] opening repo with go-git: %v\n", err)
+		os.Exit(1)
+	}
+	return &goGitRepo{repo: r}
+}
+
+func (g *goGitRepo) Root() string {
+	wt, err := g.repo.Worktree()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[error] resolving worktree root: %v\n", err)
+		os.Exit(1)
+	}
+	return wt.Filesystem.Root()
+}
+
+func (g *goGitRepo) CurrentBranch() string {
+	head, err := g.repo.Head()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[error] resolving HEAD: %v\n", err)
+		os.Exit(1)
+	}
+	return head.Name().Short()
+}
+
+func (g *goGitRepo) DefaultBranch(remote string) string {
+	ref, err := g.repo.Reference(plumbing.NewRemoteHEADReferenceName(remote), true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[error] resolving default branch for remote %q: %v\n", remote, err)
+		os.Exit(1)
+	}
+	// ref resolves to refs/remotes/<remote>/<branch>, whose Short() form
+	// still carries the remote name; callers want the bare branch name.
+	return strings.TrimPrefix(ref.Name().Short(), remote+"/")
+}
+
+// mergedSet walks the commit history reachable from ref and returns the set
+// of commit hashes it contains, so membership of a branch's tip can be
+// checked with a map lookup instead of forking `git branch --merged`.
+func (g *goGitRepo) mergedSet(ref *plumbing.Reference) map[plumbing.Hash]struct{} {
+	commit, err := g.repo.CommitObject(ref.Hash())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[error] resolving commit %s: %v\n", ref.Hash(), err)
+		os.Exit(1)
+	}
+	iter := object.NewCommitIterBSF(commit, nil, nil)
+	set := map[plumbing.Hash]struct{}{}
+	if err := iter.ForEach(func(c *object.Commit) error {
+		set[c.Hash] = struct{}{}
+		return nil
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "[error] walking history: %v\n", err)
+		os.Exit(1)
+	}
+	return set
+}
+
+func (g *goGitRepo) MergedBranches() []string {
+	head, err := g.repo.Head()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[error] resolving HEAD: %v\n", err)
+		os.Exit(1)
+	}
+	merged := g.mergedSet(head)
+
+	branches, err := g.repo.Branches()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[error] listing branches: %v\n", err)
+		os.Exit(1)
+	}
+	current := head.Name()
+	names := []string{}
+	if err := branches.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name() == current {
+			return nil
+		}
+		if _, ok := merged[ref.Hash()]; ok {
+			names = append(names, ref.Name().Short())
+		}
+		return nil
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "[error] listing branches: %v\n", err)
+		os.Exit(1)
+	}
+	return names
+}
+
+func (g *goGitRepo) MergedRemoteBranches(remote, defaultBranch string) []string {
+	ref, err := g.repo.Reference(plumbing.NewRemoteReferenceName(remote, defaultBranch), true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[error] resolving %s/%s: %v\n", remote, defaultBranch, err)
+		os.Exit(1)
+	}
+	merged := g.mergedSet(ref)
+
+	refs, err := g.repo.References()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[error] listing refs: %v\n", err)
+		os.Exit(1)
+	}
+	prefix := "refs/remotes/" + remote + "/"
+	names := []string{}
+	if err := refs.ForEach(func(r *plumbing.Reference) error {
+		name := r.Name().String()
+		if len(name) <= len(prefix) || name[:len(prefix)] != prefix {
+			return nil
+		}
+		branch := name[len(prefix):]
+		if branch == defaultBranch || branch == "HEAD" {
+			return nil
+		}
+		if _, ok := merged[r.Hash()]; ok {
+			names = append(names, branch)
+		}
+		return nil
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "[error] listing refs: %v\n", err)
+		os.Exit(1)
+	}
+	return names
+}
+
+func (g *goGitRepo) Branches() []string {
+	branches, err := g.repo.Branches()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[error] listing branches: %v\n", err)
+		os.Exit(1)
+	}
+	names := []string{}
+	if err := branches.ForEach(func(ref *plumbing.Reference) error {
+		names = append(names, ref.Name().Short())
+		return nil
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "[error] listing branches: %v\n", err)
+		os.Exit(1)
+	}
+	return names
+}
+
+func (g *goGitRepo) DeleteBranch(branch string) {
+	name := plumbing.NewBranchReferenceName(branch)
+	if err := g.repo.Storer.RemoveReference(name); err != nil {
+		fmt.Fprintf(os.Stderr, "[error] deleting branch %s: %v\n", branch, err)
+		os.Exit(1)
+	}
+}
+
+func (g *goGitRepo) ForceDeleteBranch(branch string) {
+	g.DeleteBranch(branch)
+}
+
+func (g *goGitRepo) CommitterDate(branch string) time.Time {
+	ref, err := g.repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[error] resolving branch %s: %v\n", branch, err)
+		os.Exit(1)
+	}
+	commit, err := g.repo.CommitObject(ref.Hash())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[error] resolving commit %s: %v\n", ref.Hash(), err)
+		os.Exit(1)
+	}
+	return commit.Committer.When
+}
+
+// ReflogDate always reports false: go-git has no public API for reading a
+// reference's reflog, so -stale-reflog isn't supported with -backend=go-git.
+func (g *goGitRepo) ReflogDate(branch string) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// FetchPrune fetches remote. Pruning stale remote-tracking refs during a
+// fetch is a PushOptions.Prune-only feature in this go-git version;
+// FetchOptions has no equivalent field, so they're left for a future real
+// `git fetch --prune`
+// to clean up. That's only relevant to the human operating the repo, not to
+// the merge detection below, which always resolves refs live.
+func (g *goGitRepo) FetchPrune(remote string) {
+	err := g.repo.Fetch(&git.FetchOptions{RemoteName: remote})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		fmt.Fprintf(os.Stderr, "[error] fetching %s: %v\n", remote, err)
+		os.Exit(1)
+	}
+}
+
+func (g *goGitRepo) DeleteRemoteBranch(remote, branch string) {
+	err := g.repo.Push(&git.PushOptions{
+		RemoteName: remote,
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(":" + plumbing.NewBranchReferenceName(branch).String()),
+		},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[error] deleting remote branch %s/%s: %v\n", remote, branch, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// logLevel categorises log events, mirroring the tags git-clean has always
+// printed ([cmd], [cmd][stdout], [cmd][stderr], [error]) plus a catch-all
+// "info" level for everything else it reports.
+type logLevel string
+
+const (
+	levelInfo   logLevel = "info"
+	levelCmd    logLevel = "cmd"
+	levelStdout logLevel = "stdout"
+	levelStderr logLevel = "stderr"
+	levelError  logLevel = "error"
+)
+
+// logHuman and logJSON are the valid values for the -log flag.
+const (
+	logHuman = "human"
+	logJSON  = "json"
+)
+
+// logger is how git-clean reports what it's doing and what subprocesses
+// print, so the rendering can be switched between the original
+// human-readable stream and JSON lines for scripts.
+type logger interface {
+	Log(level logLevel, msg string)
+	// Writer returns an io.Writer that logs each line written to it at
+	// level, for streaming subprocess output.
+	Writer(level logLevel) io.Writer
+	// Summary reports the final counts once git-clean has finished, so
+	// automation doesn't have to parse the preceding lines.
+	Summary(deleted, protectedCount, skipped int)
+}
+
+func newLogger(format string) logger {
+	switch format {
+	case logHuman, "":
+		return humanLogger{}
+	case logJSON:
+		return jsonLogger{}
+	default:
+		fmt.Fprintf(os.Stderr, "[error] unknown -log %q, want %q or %q\n", format, logHuman, logJSON)
+		os.Exit(1)
+		return nil
+	}
+}
+
+// humanLogger reproduces git-clean's original prefixed-line output.
+type humanLogger struct{}
+
+func (humanLogger) prefix(level logLevel) string {
+	switch level {
+	case levelCmd:
+		return "[cmd] "
+	case levelStdout:
+		return "[cmd][stdout] "
+	case levelStderr:
+		return "[cmd][stderr] "
+	case levelError:
+		return "[error] "
+	default:
+		return ""
+	}
+}
+
+func (h humanLogger) Log(level logLevel, msg string) {
+	w := io.Writer(os.Stdout)
+	if level == levelError || level == levelStderr {
+		w = os.Stderr
+	}
+	fmt.Fprintf(w, "%s%s\n", h.prefix(level), msg)
+}
+
+func (h humanLogger) Writer(level logLevel) io.Writer {
+	w := io.Writer(os.Stdout)
+	if level == levelStderr {
+		w = os.Stderr
+	}
+	return &prefixWriter{prefix: h.prefix(level), w: w}
+}
+
+func (humanLogger) Summary(deleted, protectedCount, skipped int) {
+	fmt.Printf("[summary] deleted=%d protected=%d skipped=%d\n", deleted, protectedCount, skipped)
+}
+
+// jsonLogger emits one JSON object per line, so git-clean can be embedded in
+// a larger automation script the way lazygit embeds per-command runners.
+type jsonLogger struct{}
+
+type logEvent struct {
+	Event   string   `json:"event"`
+	Level   logLevel `json:"level,omitempty"`
+	Message string   `json:"message,omitempty"`
+
+	Deleted   *int `json:"deleted,omitempty"`
+	Protected *int `json:"protected,omitempty"`
+	Skipped   *int `json:"skipped,omitempty"`
+}
+
+func (jsonLogger) emit(e logEvent) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[error] marshalling log event: %v\n", err)
+		return
+	}
+	fmt.Println(string(b))
+}
+
+func (l jsonLogger) Log(level logLevel, msg string) {
+	l.emit(logEvent{Event: "log", Level: level, Message: msg})
+}
+
+func (l jsonLogger) Writer(level logLevel) io.Writer {
+	return &lineWriter{onLine: func(line string) { l.Log(level, line) }}
+}
+
+func (l jsonLogger) Summary(deleted, protectedCount, skipped int) {
+	l.emit(logEvent{Event: "summary", Deleted: &deleted, Protected: &protectedCount, Skipped: &skipped})
+}
+
+// lineWriter buffers writes until a full line is available and hands each
+// one to onLine, so a JSON renderer never has to emit a partial line.
+type lineWriter struct {
+	onLine func(line string)
+	buf    bytes.Buffer
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	lw.buf.Write(p)
+	for {
+		b := lw.buf.Bytes()
+		idx := bytes.IndexByte(b, '\n')
+		if idx < 0 {
+			break
+		}
+		lw.onLine(string(b[:idx]))
+		lw.buf.Next(idx + 1)
+	}
+	return len(p), nil
+}
+
+// prefixWriter writes each line it receives to w, prefixed with prefix. It
+// streams partial lines as they arrive rather than buffering until a
+// newline, so interactive subprocess output still appears live.
+type prefixWriter struct {
+	started bool
+	prefix  string
+	w       io.Writer
+}
+
+func (pw *prefixWriter) Write(p []byte) (int, error) {
+	for start := 0; start < len(p); {
+		newlineIdx := -1
+		for i := start; i < len(p); i++ {
+			if p[i] == '\n' {
+				newlineIdx = i
+				break
+			}
+		}
+		if newlineIdx == -1 {
+			// in a line
+			n, err := pw.writeOnce(p[start:])
+			if err == nil {
+				pw.started = true
+			}
+			return start + n, err
+		}
+		n, err := pw.writeOnce(p[start : newlineIdx+1])
+		if err != nil {
+			return start + n, err
+		}
+		pw.started = false
+		start = newlineIdx + 1
+	}
+	return len(p), nil
+}
+
+func (pw *prefixWriter) writeOnce(p []byte) (int, error) {
+	if !pw.started {
+		if _, err := pw.w.Write([]byte(pw.prefix)); err != nil {
+			return 0, err
+		}
+	}
+	return pw.w.Write(p)
+}
+
+// shellQuoteCommand renders name and args as a command line that can be
+// copy-pasted into a shell, quoting any argument that needs it.
+func shellQuoteCommand(name string, args []string) string {
+	parts := make([]string, 0, len(args)+1)
+	parts = append(parts, shellQuoteArg(name))
+	for _, a := range args {
+		parts = append(parts, shellQuoteArg(a))
+	}
+	return strings.Join(parts, " ")
+}
+
+func shellQuoteArg(s string) string {
+	if runtime.GOOS == "windows" {
+		return windowsQuoteArg(s)
+	}
+	return unixQuoteArg(s)
+}
+
+// unixQuoteArg single-quotes s if needed, escaping an embedded single quote
+// by closing the quoted string, emitting an escaped quote, then reopening.
+func unixQuoteArg(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`!*?[](){}<>|;&~#") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// windowsQuoteArg double-quotes s if needed. cmd.exe treats &|<>^ as
+// literal characters inside a double-quoted string, so only an embedded
+// quote needs escaping there; caret-escaping only matters outside quotes,
+// and combining both would make the printed command re-parse with a
+// spurious literal caret.
+func windowsQuoteArg(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"^&|<>") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestUnixQuoteArg(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"no special chars", "origin", "origin"},
+		{"empty string", "", "''"},
+		{"space", "a b", "'a b'"},
+		{"embedded single quote", "it's", `'it'\''s'`},
+		{"glob metacharacter", "release/*", "'release/*'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unixQuoteArg(tt.in); got != tt.want {
+				t.Errorf("unixQuoteArg(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowsQuoteArg(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"no special chars", "origin", "origin"},
+		{"empty string", "", `""`},
+		{"space", "a b", `"a b"`},
+		{"ampersand left literal inside quotes", "a&b", `"a&b"`},
+		{"embedded quote escaped", `a"b`, `"a\"b"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := windowsQuoteArg(tt.in); got != tt.want {
+				t.Errorf("windowsQuoteArg(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellQuoteCommand(t *testing.T) {
+	got := shellQuoteCommand("git", []string{"commit", "-m", "a message"})
+	want := `git commit -m 'a message'`
+	if got != want {
+		t.Errorf("shellQuoteCommand() = %q, want %q", got, want)
+	}
+}
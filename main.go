@@ -1,6 +1,25 @@
 // Short script for performing cleanup on a git repo. Use the
 // flag -h for options. Performs a dry run by default. Provide the
 // flag -f to perform the commands.
+//
+// Pass -prune to also clean up merged branches on a remote (-remote,
+// defaults to "origin"): this fetches with --prune and then offers to
+// delete each remote branch merged into the remote's default branch.
+// With -backend=go-git the fetch does not prune stale remote-tracking refs.
+//
+// Branches can be protected from deletion, even when merged, via a
+// .git-clean.yaml in the repo root and/or $XDG_CONFIG_HOME/git-clean/config.yaml.
+// See config.go.
+//
+// Branch operations are performed through the repo interface (see repo.go),
+// backed by shelling out to the git binary by default, or by go-git when
+// -backend=go-git is passed.
+//
+// Pass -stale <duration> to additionally force-delete local branches with
+// no recent activity, regardless of merge status. See stale.go.
+//
+// Pass -log=json to render output as JSON lines instead of the default
+// human-readable text, ending with a summary event. See logging.go.
 package main
 
 import (
@@ -12,77 +31,146 @@ import (
 	"io"
 	"os"
 	"os/exec"
-	"regexp"
 	"strings"
 	"time"
 )
 
 var (
-	force   = flag.Bool("f", false, "Perform cleanup options.")
-	verbose = flag.Bool("v", false, "Verbose output.")
+	force       = flag.Bool("f", false, "Perform cleanup options.")
+	verbose     = flag.Bool("v", false, "Verbose output.")
+	remote      = flag.String("remote", "origin", "Name of the remote to operate on.")
+	prune       = flag.Bool("prune", false, "Also clean up merged branches on the remote. Runs `git fetch --prune` first.")
+	yes         = flag.Bool("y", false, "Skip the confirmation prompts when deleting remote or stale branches.")
+	backend     = flag.String("backend", backendGit, "Backend to use for git operations: \"git\" (shell out to the git binary) or \"go-git\".")
+	stale       = flag.Duration("stale", 0, "Also force-delete local branches with no activity for longer than this duration (e.g. -stale 2160h for 90 days), regardless of merge status.")
+	staleReflog = flag.Bool("stale-reflog", false, "With -stale, judge a branch's age by its most recent reflog entry instead of its tip commit's committer date.")
+	logFormat   = flag.String("log", logHuman, "Output format: \"human\" or \"json\".")
 )
 
+// lg is the logger selected by -log, used throughout main and by runCmd.
+var lg logger
+
 func main() {
 	flag.Parse()
+	lg = newLogger(*logFormat)
+
+	r := newRepo(*backend)
+
+	cfg, err := loadConfig(r.Root())
+	if err != nil {
+		lg.Log(levelError, fmt.Sprintf("loading config: %v", err))
+		os.Exit(1)
+	}
+	protected := compileProtected(cfg.Protected)
 
-	currentBranch := getCurrentBranch()
+	currentBranch := r.CurrentBranch()
 	{
-		defaultBranch := getDefaultBranch()
+		defaultBranch := resolveDefaultBranch(r, cfg, *remote)
 		if currentBranch != defaultBranch {
-			fmt.Fprintf(os.Stderr, "[error] Refusing to run `git-clean` without being on the default branch. Currently on branch %s. Default branch %s.\n", currentBranch, defaultBranch)
+			lg.Log(levelError, fmt.Sprintf("Refusing to run `git-clean` without being on the default branch. Currently on branch %s. Default branch %s.", currentBranch, defaultBranch))
 			os.Exit(1)
 		}
 	}
 
-	mergedBranches := getMergedBranches()
+	var deleted, protectedCount, skipped int
+
+	mergedBranches := r.MergedBranches()
 	for _, branch := range mergedBranches {
+		if protected.match(branch) {
+			lg.Log(levelInfo, fmt.Sprintf("[protected] %s", branch))
+			protectedCount++
+			continue
+		}
 		msg := "[deleting]"
 		if !*force {
 			msg = "[would delete]"
 		}
-		fmt.Printf("%s %s\n", msg, branch)
+		lg.Log(levelInfo, fmt.Sprintf("%s %s", msg, branch))
 		if *force {
-			deleteBranch(branch)
+			r.DeleteBranch(branch)
+			deleted++
 		}
 	}
-}
 
-var defaultBranchRegexp = regexp.MustCompile(`HEAD branch: (.*)`)
+	if *prune {
+		d, p, s := cleanRemoteBranches(r, *remote, cfg, protected)
+		deleted += d
+		protectedCount += p
+		skipped += s
+	}
 
-func getDefaultBranch() string {
-	out := runCmdDefaultTimeout("git", "remote", "show", "origin")
-	bb := defaultBranchRegexp.FindSubmatch(out)
-	if len(bb) != 2 {
-		fmt.Fprint(os.Stderr, "[error] failed to extract default branch")
-		os.Exit(1)
+	if *stale > 0 {
+		d, p, s := cleanStaleBranches(r, currentBranch, protected, *stale, *staleReflog)
+		deleted += d
+		protectedCount += p
+		skipped += s
 	}
 
-	return strings.TrimSpace(string(bb[1]))
+	lg.Summary(deleted, protectedCount, skipped)
 }
 
-func getCurrentBranch() string {
-	out := runCmdDefaultTimeout("git", "rev-parse", "--abbrev-ref", "HEAD")
-	return string(bytes.TrimSpace(out))
+// resolveDefaultBranch honours cfg.DefaultBranch when set, avoiding a
+// `git remote show` round trip for repos where that is slow or unavailable.
+func resolveDefaultBranch(r repo, cfg cleanConfig, remote string) string {
+	if cfg.DefaultBranch != "" {
+		return cfg.DefaultBranch
+	}
+	return r.DefaultBranch(remote)
 }
 
-func getMergedBranches() []string {
-	out := runCmdDefaultTimeout("git", "branch", "--merged")
-	scanner := bufio.NewScanner(bytes.NewReader(out))
-	branches := []string{}
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if !strings.HasPrefix(line, "* ") {
-			branches = append(branches, line)
+// cleanRemoteBranches fetches and prunes the given remote, then offers to
+// delete its merged branches. It mirrors the local cleanup in main but
+// operates on remote-tracking refs and pushes deletions rather than
+// running them locally. It returns the number of branches deleted,
+// protected, and skipped.
+func cleanRemoteBranches(r repo, remote string, cfg cleanConfig, protected protectedMatcher) (deleted, protectedCount, skipped int) {
+	r.FetchPrune(remote)
+
+	defaultBranch := resolveDefaultBranch(r, cfg, remote)
+	candidates := r.MergedRemoteBranches(remote, defaultBranch)
+
+	var branches []string
+	for _, branch := range candidates {
+		if protected.match(branch) {
+			lg.Log(levelInfo, fmt.Sprintf("[protected] %s/%s", remote, branch))
+			protectedCount++
+			continue
+		}
+		branches = append(branches, branch)
+	}
+	if len(branches) == 0 {
+		return deleted, protectedCount, skipped
+	}
+
+	for _, branch := range branches {
+		msg := "[deleting remote]"
+		if !*force {
+			msg = "[would delete remote]"
 		}
+		lg.Log(levelInfo, fmt.Sprintf("%s %s/%s", msg, remote, branch))
 	}
-	if err := scanner.Err(); err != nil {
-		fmt.Fprintf(os.Stderr, "[error] scanner error %v\n", err)
+
+	if !*force {
+		return deleted, protectedCount, skipped
+	}
+	if !*yes && !confirm(fmt.Sprintf("Delete %d merged branch(es) on remote %q?", len(branches), remote)) {
+		lg.Log(levelInfo, "[skipped] remote branch deletion")
+		return deleted, protectedCount, len(branches)
 	}
-	return branches
+	for _, branch := range branches {
+		r.DeleteRemoteBranch(remote, branch)
+	}
+	return len(branches), protectedCount, skipped
 }
 
-func deleteBranch(branch string) {
-	runCmdDefaultTimeout("git", "branch", "-d", branch)
+// confirm writes prompt to stderr, not stdout, so it never lands in the
+// JSON-lines stream -log=json produces there.
+func confirm(prompt string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", prompt)
+	reader := bufio.NewReader(os.Stdin)
+	answer, _ := reader.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
 }
 
 func runCmdDefaultTimeout(name string, args ...string) []byte {
@@ -93,65 +181,21 @@ func runCmdDefaultTimeout(name string, args ...string) []byte {
 
 func runCmd(ctx context.Context, name string, args ...string) []byte {
 	cmd := exec.CommandContext(ctx, name, args...)
-	// FIXME: use a command strings that can be copy-pasted into the shell
 	if *verbose {
-		fmt.Printf("[cmd] %s\n", cmd.String())
-	} // if
+		lg.Log(levelCmd, shellQuoteCommand(name, args))
+	}
 
-	cmd.Stderr = &prefixWriter{prefix: "[cmd][stderr] ", w: os.Stderr}
+	cmd.Stderr = lg.Writer(levelStderr)
 	stdout := &bytes.Buffer{}
+	cmd.Stdout = stdout
 	if *verbose {
-		cmd.Stdout = io.MultiWriter(&prefixWriter{prefix: "[cmd][stdout] ", w: os.Stdout}, stdout)
-	} else {
-		cmd.Stdout = stdout
+		cmd.Stdout = io.MultiWriter(lg.Writer(levelStdout), stdout)
 	}
 
 	if err := cmd.Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "[error] error running command %s, %v\n", cmd.String(), err)
+		lg.Log(levelError, fmt.Sprintf("error running command %s, %v", shellQuoteCommand(name, args), err))
 		os.Exit(1)
 	}
 
 	return stdout.Bytes()
 }
-
-type prefixWriter struct {
-	started bool
-	prefix  string
-	w       io.Writer
-}
-
-func (pw *prefixWriter) Write(p []byte) (int, error) {
-	for start := 0; start < len(p); {
-		newlineIdx := -1
-		for i := start; i < len(p); i++ {
-			if p[i] == '\n' {
-				newlineIdx = i
-				break
-			}
-		}
-		if newlineIdx == -1 {
-			// in a line
-			n, err := pw.writeOnce(p[start:])
-			if err == nil {
-				pw.started = true
-			}
-			return start + n, err
-		}
-		n, err := pw.writeOnce(p[start : newlineIdx+1])
-		if err != nil {
-			return start + n, err
-		}
-		pw.started = false
-		start = newlineIdx + 1
-	}
-	return len(p), nil
-}
-
-func (pw *prefixWriter) writeOnce(p []byte) (int, error) {
-	if !pw.started {
-		if _, err := pw.w.Write([]byte(pw.prefix)); err != nil {
-			return 0, err
-		}
-	}
-	return pw.w.Write(p)
-}
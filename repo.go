@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// backendGit and backendGoGit are the valid values for the -backend flag.
+const (
+	backendGit   = "git"
+	backendGoGit = "go-git"
+)
+
+var defaultBranchRegexp = regexp.MustCompile(`HEAD branch: (.*)`)
+
+// repo abstracts the branch operations git-clean needs so they can be
+// backed either by shelling out to the git binary or by go-git, for hosts
+// that don't have a git binary available (containers, embedded CI).
+type repo interface {
+	// Root returns the absolute path to the root of the working tree.
+	Root() string
+	CurrentBranch() string
+	DefaultBranch(remote string) string
+	Branches() []string
+	MergedBranches() []string
+	MergedRemoteBranches(remote, defaultBranch string) []string
+	DeleteBranch(branch string)
+	ForceDeleteBranch(branch string)
+
+	// CommitterDate returns the committer timestamp of branch's tip commit.
+	CommitterDate(branch string) time.Time
+	// ReflogDate returns the timestamp of branch's most recent reflog entry,
+	// and false if the branch has no reflog.
+	ReflogDate(branch string) (time.Time, bool)
+
+	FetchPrune(remote string)
+	DeleteRemoteBranch(remote, branch string)
+}
+
+// newRepo constructs the repo implementation named by backend, exiting with
+// an error if the name isn't recognised.
+func newRepo(backend string) repo {
+	switch backend {
+	case backendGit:
+		return gitRepo{}
+	case backendGoGit:
+		return newGoGitRepo(".")
+	default:
+		fmt.Fprintf(os.Stderr, "[error] unknown -backend %q, want %q or %q\n", backend, backendGit, backendGoGit)
+		os.Exit(1)
+		return nil
+	}
+}
+
+// gitRepo implements repo by shelling out to the git binary, as git-clean
+// has always done.
+type gitRepo struct{}
+
+func (gitRepo) Root() string {
+	out := runCmdDefaultTimeout("git", "rev-parse", "--show-toplevel")
+	return string(bytes.TrimSpace(out))
+}
+
+func (gitRepo) CurrentBranch() string {
+	out := runCmdDefaultTimeout("git", "rev-parse", "--abbrev-ref", "HEAD")
+	return string(bytes.TrimSpace(out))
+}
+
+func (gitRepo) DefaultBranch(remote string) string {
+	out := runCmdDefaultTimeout("git", "remote", "show", remote)
+	bb := defaultBranchRegexp.FindSubmatch(out)
+	if len(bb) != 2 {
+		fmt.Fprint(os.Stderr, "[error] failed to extract default branch")
+		os.Exit(1)
+	}
+	return strings.TrimSpace(string(bb[1]))
+}
+
+func (gitRepo) MergedBranches() []string {
+	out := runCmdDefaultTimeout("git", "branch", "--merged")
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	branches := []string{}
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "* ") {
+			branches = append(branches, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "[error] scanner error %v\n", err)
+	}
+	return branches
+}
+
+func (gitRepo) MergedRemoteBranches(remote, defaultBranch string) []string {
+	out := runCmdDefaultTimeout("git", "branch", "-r", "--merged", remote+"/"+defaultBranch)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	prefix := remote + "/"
+	branches := []string{}
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, prefix) || strings.Contains(line, "->") {
+			continue
+		}
+		branch := strings.TrimPrefix(line, prefix)
+		if branch == defaultBranch {
+			continue
+		}
+		branches = append(branches, branch)
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "[error] scanner error %v\n", err)
+	}
+	return branches
+}
+
+func (gitRepo) Branches() []string {
+	out := runCmdDefaultTimeout("git", "branch", "--format=%(refname:short)")
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	branches := []string{}
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			branches = append(branches, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "[error] scanner error %v\n", err)
+	}
+	return branches
+}
+
+func (gitRepo) DeleteBranch(branch string) {
+	runCmdDefaultTimeout("git", "branch", "-d", branch)
+}
+
+func (gitRepo) ForceDeleteBranch(branch string) {
+	runCmdDefaultTimeout("git", "branch", "-D", branch)
+}
+
+func (gitRepo) CommitterDate(branch string) time.Time {
+	out := runCmdDefaultTimeout("git", "log", "-1", "--format=%ct", branch)
+	sec, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[error] parsing committer date for %s: %v\n", branch, err)
+		os.Exit(1)
+	}
+	return time.Unix(sec, 0)
+}
+
+var reflogTimestampRegexp = regexp.MustCompile(`@\{(\d+)`)
+
+func (gitRepo) ReflogDate(branch string) (time.Time, bool) {
+	out := runCmdDefaultTimeout("git", "reflog", "show", "--date=unix", branch)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	if !scanner.Scan() {
+		return time.Time{}, false
+	}
+	bb := reflogTimestampRegexp.FindSubmatch(scanner.Bytes())
+	if len(bb) != 2 {
+		return time.Time{}, false
+	}
+	sec, err := strconv.ParseInt(string(bb[1]), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, 0), true
+}
+
+func (gitRepo) FetchPrune(remote string) {
+	runCmdDefaultTimeout("git", "fetch", remote, "--prune")
+}
+
+func (gitRepo) DeleteRemoteBranch(remote, branch string) {
+	runCmdDefaultTimeout("git", "push", remote, "--delete", branch)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// cleanStaleBranches deletes local branches whose tip has had no activity
+// for longer than threshold, regardless of merge status. It complements the
+// merged-branch cleanup in main, which only ever considers merged branches.
+// Since force-deleting an unmerged branch can lose commits, it always goes
+// through the repo's force delete (git branch -D with the git backend) and
+// an extra confirmation (unless -y is set). It
+// returns the number of branches deleted, protected, and skipped.
+func cleanStaleBranches(r repo, currentBranch string, protected protectedMatcher, threshold time.Duration, useReflog bool) (deleted, protectedCount, skipped int) {
+	now := time.Now()
+
+	var stale []string
+	for _, branch := range r.Branches() {
+		if branch == currentBranch {
+			continue
+		}
+		if protected.match(branch) {
+			lg.Log(levelInfo, fmt.Sprintf("[protected] %s", branch))
+			protectedCount++
+			continue
+		}
+		age := branchAge(r, branch, useReflog)
+		if now.Sub(age) < threshold {
+			continue
+		}
+		stale = append(stale, branch)
+		msg := "[deleting stale]"
+		if !*force {
+			msg = "[would delete stale]"
+		}
+		lg.Log(levelInfo, fmt.Sprintf("%s %s (last activity %s ago)", msg, branch, now.Sub(age).Round(time.Hour)))
+	}
+	if len(stale) == 0 || !*force {
+		return deleted, protectedCount, skipped
+	}
+
+	if !*yes && !confirm(fmt.Sprintf("Force-delete %d stale branch(es)? This can discard unmerged commits.", len(stale))) {
+		lg.Log(levelInfo, "[skipped] stale branch deletion")
+		return deleted, protectedCount, len(stale)
+	}
+	for _, branch := range stale {
+		r.ForceDeleteBranch(branch)
+	}
+	return len(stale), protectedCount, skipped
+}
+
+// branchAge resolves the timestamp used to judge a branch's staleness:
+// its most recent reflog entry if useReflog is set and one exists,
+// otherwise its tip commit's committer date.
+func branchAge(r repo, branch string, useReflog bool) time.Time {
+	if useReflog {
+		if t, ok := r.ReflogDate(branch); ok {
+			return t
+		}
+	}
+	return r.CommitterDate(branch)
+}
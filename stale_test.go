@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeRepo is a minimal repo implementation for exercising branchAge without
+// a real git checkout; only CommitterDate and ReflogDate are exercised.
+type fakeRepo struct {
+	committerDate time.Time
+	reflogDate    time.Time
+	hasReflog     bool
+}
+
+func (fakeRepo) Root() string                                               { return "" }
+func (fakeRepo) CurrentBranch() string                                      { return "" }
+func (fakeRepo) DefaultBranch(remote string) string                         { return "" }
+func (fakeRepo) Branches() []string                                         { return nil }
+func (fakeRepo) MergedBranches() []string                                   { return nil }
+func (fakeRepo) MergedRemoteBranches(remote, defaultBranch string) []string { return nil }
+func (fakeRepo) DeleteBranch(branch string)                                 {}
+func (fakeRepo) ForceDeleteBranch(branch string)                            {}
+func (f fakeRepo) CommitterDate(branch string) time.Time                    { return f.committerDate }
+func (f fakeRepo) ReflogDate(branch string) (time.Time, bool)               { return f.reflogDate, f.hasReflog }
+func (fakeRepo) FetchPrune(remote string)                                   {}
+func (fakeRepo) DeleteRemoteBranch(remote, branch string)                   {}
+
+func TestBranchAge(t *testing.T) {
+	committed := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	reflogged := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		r         fakeRepo
+		useReflog bool
+		want      time.Time
+	}{
+		{"committer date when reflog not requested", fakeRepo{committerDate: committed, reflogDate: reflogged, hasReflog: true}, false, committed},
+		{"reflog date when requested and present", fakeRepo{committerDate: committed, reflogDate: reflogged, hasReflog: true}, true, reflogged},
+		{"falls back to committer date when reflog requested but absent", fakeRepo{committerDate: committed, hasReflog: false}, true, committed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := branchAge(tt.r, "branch", tt.useReflog); !got.Equal(tt.want) {
+				t.Errorf("branchAge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}